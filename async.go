@@ -0,0 +1,272 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	asyncSent    = expvar.NewInt("relay_async_sent")
+	asyncDropped = expvar.NewInt("relay_async_dropped")
+	asyncFailed  = expvar.NewInt("relay_async_failed")
+)
+
+// DropPolicy controls what an AsyncRelay does when its queue is full.
+type DropPolicy int
+
+const (
+	// DropOldest discards the oldest queued item to make room for the
+	// new one.
+	DropOldest DropPolicy = iota
+	// DropNewest discards the incoming item, leaving the queue as-is.
+	DropNewest
+	// Block waits for room in the queue, applying backpressure to the
+	// caller of Send.
+	Block
+)
+
+// AsyncOptions configures an AsyncRelay.
+type AsyncOptions struct {
+	// QueueSize bounds how many messages can be buffered waiting for a
+	// worker. Defaults to 100.
+	QueueSize int
+
+	// Workers is the number of goroutines draining the queue.
+	// Defaults to 1.
+	Workers int
+
+	// FlushInterval is how often the background loop checks for
+	// coalesced messages whose window has elapsed. Defaults to 1s.
+	FlushInterval time.Duration
+
+	// DropPolicy controls behavior when the queue is full. Defaults
+	// to DropOldest.
+	DropPolicy DropPolicy
+
+	// Coalesce, when greater than zero, merges identical (subject,
+	// err.Error()) pairs seen within this window into a single "N
+	// occurrences" message instead of sending one per call.
+	Coalesce time.Duration
+}
+
+type asyncJob struct {
+	subject string
+	err     error
+}
+
+type asyncItem struct {
+	subject string
+	errText string
+	count   int
+	first   time.Time
+}
+
+// AsyncRelay wraps a Relay with a bounded in-memory queue and worker
+// pool so Send never blocks the caller (except under DropPolicy
+// Block), making it safe to call from hot paths like panic handlers or
+// HTTP middleware.
+type AsyncRelay struct {
+	relay *Relay
+	opts  AsyncOptions
+
+	queue     chan asyncJob
+	closeCh   chan struct{}
+	runDone   chan struct{}
+	closeOnce sync.Once
+	workerWG  sync.WaitGroup
+
+	mu        sync.Mutex
+	coalesced map[string]*asyncItem
+
+	// inFlight counts jobs a worker has dequeued but not yet finished
+	// sending, so Flush can wait for actual delivery instead of just an
+	// empty channel buffer.
+	inFlight int64
+}
+
+// NewAsync builds a Relay from cfg and wraps it in an AsyncRelay.
+func NewAsync(cfg *Config, opts AsyncOptions) (*AsyncRelay, error) {
+	r, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAsyncRelay(r, opts), nil
+}
+
+func newAsyncRelay(r *Relay, opts AsyncOptions) *AsyncRelay {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+
+	ar := &AsyncRelay{
+		relay:     r,
+		opts:      opts,
+		queue:     make(chan asyncJob, opts.QueueSize),
+		closeCh:   make(chan struct{}),
+		runDone:   make(chan struct{}),
+		coalesced: make(map[string]*asyncItem),
+	}
+
+	go ar.run()
+	for i := 0; i < opts.Workers; i++ {
+		ar.workerWG.Add(1)
+		go ar.work()
+	}
+
+	return ar
+}
+
+// Send enqueues subject/err for asynchronous delivery. It does not
+// return an error: delivery happens on a worker goroutine, and
+// failures are only observable via the relay_async_failed expvar
+// counter.
+func (ar *AsyncRelay) Send(subject string, err error) {
+	if ar.opts.Coalesce <= 0 {
+		ar.enqueue(asyncJob{subject: subject, err: err})
+		return
+	}
+
+	key := subject + "\x00" + err.Error()
+
+	ar.mu.Lock()
+	item, ok := ar.coalesced[key]
+	if !ok {
+		item = &asyncItem{subject: subject, errText: err.Error(), first: time.Now()}
+		ar.coalesced[key] = item
+	}
+	item.count++
+	ar.mu.Unlock()
+}
+
+// enqueue applies the DropPolicy to get job onto the queue.
+func (ar *AsyncRelay) enqueue(job asyncJob) {
+	select {
+	case ar.queue <- job:
+		return
+	default:
+	}
+
+	switch ar.opts.DropPolicy {
+	case Block:
+		select {
+		case ar.queue <- job:
+		case <-ar.closeCh:
+		}
+	case DropOldest:
+		select {
+		case <-ar.queue:
+			asyncDropped.Add(1)
+		default:
+		}
+		select {
+		case ar.queue <- job:
+		default:
+			asyncDropped.Add(1)
+		}
+	default: // DropNewest
+		asyncDropped.Add(1)
+	}
+}
+
+func (ar *AsyncRelay) run() {
+	defer close(ar.runDone)
+
+	ticker := time.NewTicker(ar.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ar.flushCoalesced(time.Now())
+		case <-ar.closeCh:
+			ar.flushCoalesced(time.Time{})
+			return
+		}
+	}
+}
+
+// flushCoalesced enqueues every coalesced item whose window has
+// elapsed as of now. A zero now forces every pending item to flush
+// regardless of age, which is what Flush and Close need.
+func (ar *AsyncRelay) flushCoalesced(now time.Time) {
+	ar.mu.Lock()
+	var ready []*asyncItem
+	for key, item := range ar.coalesced {
+		if now.IsZero() || now.Sub(item.first) >= ar.opts.Coalesce {
+			ready = append(ready, item)
+			delete(ar.coalesced, key)
+		}
+	}
+	ar.mu.Unlock()
+
+	for _, item := range ready {
+		ar.enqueue(asyncJob{subject: item.subject, err: coalescedError(item)})
+	}
+}
+
+func coalescedError(item *asyncItem) error {
+	if item.count <= 1 {
+		return errors.New(item.errText)
+	}
+	return fmt.Errorf("%s (%d occurrences)", item.errText, item.count)
+}
+
+func (ar *AsyncRelay) work() {
+	defer ar.workerWG.Done()
+
+	for job := range ar.queue {
+		atomic.AddInt64(&ar.inFlight, 1)
+		err := ar.relay.SendContext(context.Background(), job.subject, job.err)
+		atomic.AddInt64(&ar.inFlight, -1)
+
+		if err != nil {
+			asyncFailed.Add(1)
+			continue
+		}
+		asyncSent.Add(1)
+	}
+}
+
+// Flush forces any pending coalesced messages onto the queue and
+// blocks until the queue has drained and every in-flight send has
+// completed, or ctx is done.
+func (ar *AsyncRelay) Flush(ctx context.Context) error {
+	ar.flushCoalesced(time.Time{})
+
+	for len(ar.queue) > 0 || atomic.LoadInt64(&ar.inFlight) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	return nil
+}
+
+// Close flushes whatever coalesced messages are pending, closes the
+// queue, and waits for the worker pool to drain it. Only the first call
+// does this; later calls just wait for the workers. Send does not check
+// whether Close has been called.
+func (ar *AsyncRelay) Close() error {
+	ar.closeOnce.Do(func() {
+		close(ar.closeCh)
+		<-ar.runDone
+		close(ar.queue)
+	})
+
+	ar.workerWG.Wait()
+	return nil
+}
@@ -0,0 +1,160 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingTransport blocks Send until release is closed, so tests can
+// hold a job "in flight" and observe how Flush/Close/drop policies
+// behave while a worker is still sending it. started, if non-nil,
+// receives a value as each Send call begins.
+type blockingTransport struct {
+	release chan struct{}
+	started chan struct{}
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (t *blockingTransport) Send(ctx context.Context, msg Message) error {
+	if t.started != nil {
+		t.started <- struct{}{}
+	}
+
+	<-t.release
+
+	t.mu.Lock()
+	t.calls++
+	t.mu.Unlock()
+
+	return nil
+}
+
+func newTestAsyncRelay(t *testing.T, transport Transport, opts AsyncOptions) *AsyncRelay {
+	t.Helper()
+
+	r := &Relay{
+		transport: transport,
+		to:        "to@example.com",
+		from:      "from@example.com",
+		retry:     newRetryPolicy(nil),
+	}
+
+	ar := newAsyncRelay(r, opts)
+	t.Cleanup(func() { ar.Close() })
+	return ar
+}
+
+func TestAsyncRelayFlushWaitsForInFlightSend(t *testing.T) {
+	transport := &blockingTransport{release: make(chan struct{})}
+	ar := newTestAsyncRelay(t, transport, AsyncOptions{QueueSize: 1, Workers: 1})
+
+	ar.Send("subject", errors.New("boom"))
+
+	// Give the worker a chance to dequeue the job before we release it,
+	// so Flush has to observe the in-flight send rather than an empty
+	// queue.
+	time.Sleep(10 * time.Millisecond)
+
+	flushDone := make(chan error, 1)
+	go func() { flushDone <- ar.Flush(context.Background()) }()
+
+	select {
+	case <-flushDone:
+		t.Fatal("Flush returned before the in-flight send completed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(transport.release)
+
+	select {
+	case err := <-flushDone:
+		if err != nil {
+			t.Fatalf("Flush returned %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Flush did not return after the send completed")
+	}
+}
+
+func TestAsyncRelayDropOldestMakesRoomForNewest(t *testing.T) {
+	transport := &blockingTransport{release: make(chan struct{}), started: make(chan struct{}, 1)}
+	defer close(transport.release)
+
+	ar := newTestAsyncRelay(t, transport, AsyncOptions{
+		QueueSize:  1,
+		Workers:    1,
+		DropPolicy: DropOldest,
+	})
+
+	// The single worker immediately dequeues this job and blocks in
+	// Send, leaving the queue empty for the next two sends to exercise
+	// the drop policy against.
+	ar.Send("held", errors.New("held"))
+	<-transport.started
+
+	ar.Send("first", errors.New("one"))
+	ar.Send("second", errors.New("two"))
+
+	if got := len(ar.queue); got != 1 {
+		t.Fatalf("len(queue) = %d, want 1", got)
+	}
+
+	job := <-ar.queue
+	if job.subject != "second" {
+		t.Fatalf("queued job.subject = %q, want %q (oldest should have been dropped)", job.subject, "second")
+	}
+}
+
+func TestAsyncRelayDropNewestDiscardsIncoming(t *testing.T) {
+	transport := &blockingTransport{release: make(chan struct{}), started: make(chan struct{}, 1)}
+	defer close(transport.release)
+
+	ar := newTestAsyncRelay(t, transport, AsyncOptions{
+		QueueSize:  1,
+		Workers:    1,
+		DropPolicy: DropNewest,
+	})
+
+	ar.Send("held", errors.New("held"))
+	<-transport.started
+
+	ar.Send("first", errors.New("one"))
+	ar.Send("second", errors.New("two"))
+
+	if got := len(ar.queue); got != 1 {
+		t.Fatalf("len(queue) = %d, want 1", got)
+	}
+
+	job := <-ar.queue
+	if job.subject != "first" {
+		t.Fatalf("queued job.subject = %q, want %q (newest should have been dropped)", job.subject, "first")
+	}
+}
+
+func TestAsyncRelayCloseDrainsQueue(t *testing.T) {
+	transport := &blockingTransport{release: make(chan struct{})}
+	close(transport.release)
+
+	ar := newTestAsyncRelay(t, transport, AsyncOptions{QueueSize: 10, Workers: 2})
+
+	for i := 0; i < 5; i++ {
+		ar.Send("subject", errors.New("boom"))
+	}
+
+	if err := ar.Close(); err != nil {
+		t.Fatalf("Close returned %v, want nil", err)
+	}
+
+	transport.mu.Lock()
+	calls := transport.calls
+	transport.mu.Unlock()
+
+	if calls != 5 {
+		t.Fatalf("transport.calls = %d, want 5 (Close should drain the queue)", calls)
+	}
+}
@@ -0,0 +1,216 @@
+package relay
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// ConfigSource produces a Config from somewhere other than a literal
+// *Config passed to New, e.g. environment variables or a JSON file.
+type ConfigSource interface {
+	Config() (*Config, error)
+}
+
+// NewFromSource builds a Relay from whatever Config src produces. It
+// is the ConfigSource equivalent of New(c).
+func NewFromSource(src ConfigSource) (*Relay, error) {
+	c, err := src.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(c)
+}
+
+// envConfigSource reads configuration from environment variables:
+// MAILGUN_API_KEY, MAILGUN_DOMAIN, RELAY_TO, RELAY_FROM.
+type envConfigSource struct{}
+
+// EnvConfig returns a ConfigSource that reads MAILGUN_API_KEY,
+// MAILGUN_DOMAIN, RELAY_TO, and RELAY_FROM from the environment.
+func EnvConfig() ConfigSource {
+	return envConfigSource{}
+}
+
+func (envConfigSource) Config() (*Config, error) {
+	c := &Config{
+		To:   os.Getenv("RELAY_TO"),
+		From: os.Getenv("RELAY_FROM"),
+	}
+
+	if key := os.Getenv("MAILGUN_API_KEY"); key != "" {
+		c.Mailgun = &MailgunConfig{Key: key}
+		if domain := os.Getenv("MAILGUN_DOMAIN"); domain != "" {
+			c.Mailgun.Domain = domain
+		}
+	}
+
+	return c, nil
+}
+
+// fileConfigSource reads configuration from a JSON file.
+type fileConfigSource struct {
+	path string
+}
+
+// FileConfig returns a ConfigSource that reads a JSON-encoded Config
+// from the file at path. New(nil) is a shorthand for
+// FileConfig("config.json").
+func FileConfig(path string) ConfigSource {
+	return fileConfigSource{path: path}
+}
+
+func (f fileConfigSource) Config() (*Config, error) {
+	infile, err := os.Open(f.path)
+	if err != nil {
+		return nil, ErrNoConfig
+	}
+	defer infile.Close()
+
+	c := new(Config)
+	if err := json.NewDecoder(infile).Decode(c); err != nil {
+		return nil, ErrBadConfig
+	}
+
+	return c, nil
+}
+
+// multiConfigSource merges several ConfigSources in order, with later
+// sources taking precedence over earlier ones field-by-field.
+type multiConfigSource struct {
+	sources []ConfigSource
+}
+
+// MultiConfig returns a ConfigSource that merges sources in order;
+// for any field a later source sets, it overrides earlier sources.
+// This is typically used to let environment variables override a base
+// file config: MultiConfig(FileConfig("config.json"), EnvConfig()).
+func MultiConfig(sources ...ConfigSource) ConfigSource {
+	return multiConfigSource{sources: sources}
+}
+
+func (m multiConfigSource) Config() (*Config, error) {
+	merged := &Config{}
+
+	for _, s := range m.sources {
+		c, err := s.Config()
+		if err != nil {
+			return nil, err
+		}
+		mergeConfig(merged, c)
+	}
+
+	return merged, nil
+}
+
+// mergeConfig copies every non-zero field of src onto dst.
+func mergeConfig(dst, src *Config) {
+	if src.Provider != "" {
+		dst.Provider = src.Provider
+	}
+	if src.To != "" {
+		dst.To = src.To
+	}
+	if src.From != "" {
+		dst.From = src.From
+	}
+	if src.Domain != "" {
+		dst.Domain = src.Domain
+	}
+	if src.Key != "" {
+		dst.Key = src.Key
+	}
+	if src.Mailgun != nil {
+		if dst.Mailgun == nil {
+			dst.Mailgun = &MailgunConfig{}
+		}
+		mergeMailgunConfig(dst.Mailgun, src.Mailgun)
+	}
+	if src.SMTP != nil {
+		if dst.SMTP == nil {
+			dst.SMTP = &SMTPConfig{}
+		}
+		mergeSMTPConfig(dst.SMTP, src.SMTP)
+	}
+	if src.SES != nil {
+		if dst.SES == nil {
+			dst.SES = &SESConfig{}
+		}
+		mergeSESConfig(dst.SES, src.SES)
+	}
+	if src.Sendgrid != nil {
+		if dst.Sendgrid == nil {
+			dst.Sendgrid = &SendgridConfig{}
+		}
+		mergeSendgridConfig(dst.Sendgrid, src.Sendgrid)
+	}
+	if src.Retry != nil {
+		dst.Retry = src.Retry
+	}
+	if src.RateLimit != nil {
+		dst.RateLimit = src.RateLimit
+	}
+}
+
+// mergeMailgunConfig copies every non-zero field of src onto dst, so a
+// source that only sets Key (e.g. EnvConfig rotating an API key) doesn't
+// wipe out Domain/Region/BaseURL an earlier source already set.
+func mergeMailgunConfig(dst, src *MailgunConfig) {
+	if src.Domain != "" {
+		dst.Domain = src.Domain
+	}
+	if src.Key != "" {
+		dst.Key = src.Key
+	}
+	if src.Region != "" {
+		dst.Region = src.Region
+	}
+	if src.BaseURL != "" {
+		dst.BaseURL = src.BaseURL
+	}
+}
+
+func mergeSMTPConfig(dst, src *SMTPConfig) {
+	if src.Host != "" {
+		dst.Host = src.Host
+	}
+	if src.Port != 0 {
+		dst.Port = src.Port
+	}
+	if src.Username != "" {
+		dst.Username = src.Username
+	}
+	if src.Password != "" {
+		dst.Password = src.Password
+	}
+	if src.UseSTARTTLS {
+		dst.UseSTARTTLS = src.UseSTARTTLS
+	}
+	if src.TLSConfig != nil {
+		dst.TLSConfig = src.TLSConfig
+	}
+}
+
+func mergeSESConfig(dst, src *SESConfig) {
+	if src.Region != "" {
+		dst.Region = src.Region
+	}
+	if src.AccessKeyID != "" {
+		dst.AccessKeyID = src.AccessKeyID
+	}
+	if src.SecretAccessKey != "" {
+		dst.SecretAccessKey = src.SecretAccessKey
+	}
+	if src.BaseURL != "" {
+		dst.BaseURL = src.BaseURL
+	}
+}
+
+func mergeSendgridConfig(dst, src *SendgridConfig) {
+	if src.Key != "" {
+		dst.Key = src.Key
+	}
+	if src.BaseURL != "" {
+		dst.BaseURL = src.BaseURL
+	}
+}
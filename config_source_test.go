@@ -0,0 +1,101 @@
+package relay
+
+import "testing"
+
+// stubConfigSource returns a fixed Config, so MultiConfig tests can
+// exercise merge precedence without touching the environment or
+// filesystem.
+type stubConfigSource struct {
+	c *Config
+}
+
+func (s stubConfigSource) Config() (*Config, error) {
+	return s.c, nil
+}
+
+func TestMultiConfigLaterSourceOverrides(t *testing.T) {
+	base := stubConfigSource{c: &Config{
+		Provider: "smtp",
+		To:       "base-to@example.com",
+		From:     "base-from@example.com",
+	}}
+	override := stubConfigSource{c: &Config{
+		To: "override-to@example.com",
+	}}
+
+	merged, err := MultiConfig(base, override).Config()
+	if err != nil {
+		t.Fatalf("Config() returned %v, want nil", err)
+	}
+
+	if merged.To != "override-to@example.com" {
+		t.Fatalf("merged.To = %q, want the later source's value", merged.To)
+	}
+	if merged.From != "base-from@example.com" {
+		t.Fatalf("merged.From = %q, want the base value to survive (later source left it unset)", merged.From)
+	}
+	if merged.Provider != "smtp" {
+		t.Fatalf("merged.Provider = %q, want the base value to survive", merged.Provider)
+	}
+}
+
+func TestMultiConfigMergesSubStructsFieldByField(t *testing.T) {
+	base := stubConfigSource{c: &Config{
+		SMTP: &SMTPConfig{Host: "base-host", Port: 25, Username: "base-user"},
+	}}
+	override := stubConfigSource{c: &Config{
+		SMTP: &SMTPConfig{Host: "override-host", Port: 587},
+	}}
+
+	merged, err := MultiConfig(base, override).Config()
+	if err != nil {
+		t.Fatalf("Config() returned %v, want nil", err)
+	}
+
+	if merged.SMTP.Host != "override-host" || merged.SMTP.Port != 587 {
+		t.Fatalf("merged.SMTP = %+v, want Host/Port from the later source", merged.SMTP)
+	}
+	if merged.SMTP.Username != "base-user" {
+		t.Fatalf("merged.SMTP.Username = %q, want the base value to survive (later source left it unset)", merged.SMTP.Username)
+	}
+}
+
+func TestMultiConfigMergesMailgunWithoutWipingDomain(t *testing.T) {
+	base := stubConfigSource{c: &Config{
+		Mailgun: &MailgunConfig{Domain: "mg.example.com", Key: "old-key"},
+	}}
+	override := stubConfigSource{c: &Config{
+		Mailgun: &MailgunConfig{Key: "new-key"},
+	}}
+
+	merged, err := MultiConfig(base, override).Config()
+	if err != nil {
+		t.Fatalf("Config() returned %v, want nil", err)
+	}
+
+	if merged.Mailgun.Key != "new-key" {
+		t.Fatalf("merged.Mailgun.Key = %q, want the later source's key", merged.Mailgun.Key)
+	}
+	if merged.Mailgun.Domain != "mg.example.com" {
+		t.Fatalf("merged.Mailgun.Domain = %q, want the base domain to survive a key-only override", merged.Mailgun.Domain)
+	}
+}
+
+func TestEnvConfigReadsEnvironment(t *testing.T) {
+	t.Setenv("RELAY_TO", "to@example.com")
+	t.Setenv("RELAY_FROM", "from@example.com")
+	t.Setenv("MAILGUN_API_KEY", "key")
+	t.Setenv("MAILGUN_DOMAIN", "example.com")
+
+	c, err := EnvConfig().Config()
+	if err != nil {
+		t.Fatalf("Config() returned %v, want nil", err)
+	}
+
+	if c.To != "to@example.com" || c.From != "from@example.com" {
+		t.Fatalf("c.To/From = %q/%q, want the values from RELAY_TO/RELAY_FROM", c.To, c.From)
+	}
+	if c.Mailgun == nil || c.Mailgun.Key != "key" || c.Mailgun.Domain != "example.com" {
+		t.Fatalf("c.Mailgun = %+v, want populated from MAILGUN_API_KEY/MAILGUN_DOMAIN", c.Mailgun)
+	}
+}
@@ -0,0 +1,180 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MailgunConfig holds the information needed to send mail through the
+// Mailgun HTTP API.
+type MailgunConfig struct {
+	Domain string `json:"domain"`
+	Key    string `json:"api_key"`
+
+	// Region selects the Mailgun API region: "us" (the default) or
+	// "eu". Ignored if BaseURL is set.
+	Region string `json:"region"`
+
+	// BaseURL overrides the API base URL entirely, e.g. to point at a
+	// relaytest server or other mock in tests.
+	BaseURL string `json:"base_url"`
+}
+
+// mailgunTransport sends messages using the Mailgun HTTP API.
+type mailgunTransport struct {
+	c       *http.Client
+	domain  string
+	key     string
+	baseURL string
+}
+
+func newMailgunTransport(cfg MailgunConfig) (*mailgunTransport, error) {
+	if cfg.Domain == "" || cfg.Key == "" {
+		return nil, ErrBadConfig
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = mailgunBaseURL(cfg.Region)
+	}
+
+	return &mailgunTransport{
+		c:       &http.Client{},
+		domain:  cfg.Domain,
+		key:     cfg.Key,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+// mailgunBaseURL maps a MailgunConfig.Region onto Mailgun's regional API
+// endpoints. An empty or unrecognized region defaults to the US API,
+// which is what "https://api.mailgun.net/v2/" always meant before
+// Region existed.
+func mailgunBaseURL(region string) string {
+	if strings.EqualFold(region, "eu") {
+		return "https://api.eu.mailgun.net/v2"
+	}
+	return "https://api.mailgun.net/v2"
+}
+
+func (t *mailgunTransport) Send(ctx context.Context, msg Message) error {
+	if len(msg.Attachments) > 0 {
+		return t.sendMultipart(ctx, msg)
+	}
+	return t.sendForm(ctx, msg)
+}
+
+func (t *mailgunTransport) sendForm(ctx context.Context, msg Message) error {
+	val := make(url.Values)
+	addCommonFields(func(k, v string) { val.Add(k, v) }, msg)
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		t.url(), bytes.NewReader([]byte(val.Encode())))
+
+	if err != nil {
+		return ErrBadRequest
+	}
+
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
+	req.SetBasicAuth("api", t.key)
+
+	res, err := t.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return statusToError(res, ErrBadRequest, ErrMailgunDown)
+}
+
+// sendMultipart is used instead of sendForm whenever the Message
+// carries attachments or inline images, which Mailgun's API requires
+// multipart/form-data to deliver.
+func (t *mailgunTransport) sendMultipart(ctx context.Context, msg Message) error {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	addCommonFields(func(k, v string) { w.WriteField(k, v) }, msg)
+
+	for _, a := range msg.Attachments {
+		fieldName := "attachment"
+		if a.Inline {
+			fieldName = "inline"
+		}
+
+		part, err := w.CreateFormFile(fieldName, a.Filename)
+		if err != nil {
+			return ErrBadRequest
+		}
+		if _, err := part.Write(a.Content); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return ErrBadRequest
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.url(), &buf)
+	if err != nil {
+		return ErrBadRequest
+	}
+
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.SetBasicAuth("api", t.key)
+
+	res, err := t.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return statusToError(res, ErrBadRequest, ErrMailgunDown)
+}
+
+func (t *mailgunTransport) url() string {
+	return t.baseURL + "/" + t.domain + "/messages"
+}
+
+// addCommonFields writes the fields shared between the form-encoded
+// and multipart requests via addField, so the two request builders
+// above don't have to duplicate the Mailgun field mapping.
+func addCommonFields(addField func(k, v string), msg Message) {
+	addField("from", msg.from)
+	if len(msg.To) > 0 {
+		addField("to", strings.Join(msg.To, ", "))
+	}
+	if len(msg.Cc) > 0 {
+		addField("cc", strings.Join(msg.Cc, ", "))
+	}
+	if len(msg.Bcc) > 0 {
+		addField("bcc", strings.Join(msg.Bcc, ", "))
+	}
+	addField("subject", msg.Subject)
+	if msg.Text != "" {
+		addField("text", msg.Text)
+	}
+	if msg.HTML != "" {
+		addField("html", msg.HTML)
+	}
+	if msg.TemplateName != "" {
+		addField("template", msg.TemplateName)
+	}
+	for _, tag := range msg.Tags {
+		addField("o:tag", tag)
+	}
+	for k, v := range msg.Vars {
+		addField("v:"+k, fmt.Sprintf("%v", v))
+	}
+	if len(msg.Vars) > 0 {
+		if b, err := json.Marshal(msg.Vars); err == nil {
+			addField("h:X-Mailgun-Variables", string(b))
+		}
+	}
+}
@@ -1,14 +1,13 @@
 /*
-Package relay implments a simple client to report errors using the Mailgun mailing service
+Package relay implments a simple client to report errors over email, via
+a pluggable Transport backend (Mailgun, SMTP, SES, Sendgrid, ...)
 */
 package relay
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"net/http"
-	"net/url"
 	"os"
 	"time"
 )
@@ -18,106 +17,189 @@ var ErrBadRequest = errors.New("the request generated by relay is invalid")
 var ErrMailgunDown = errors.New("something seems to be wrong with Mailgun servers")
 var ErrUnknown = errors.New("something undefined happened")
 var ErrNoConfig = errors.New("no Config object provided and config.json does not exist")
+var ErrUnknownProvider = errors.New("config.Provider does not name a known Transport backend")
+var ErrSTARTTLSUnavailable = errors.New("smtp: server does not advertise STARTTLS")
 
 // Relay is a client to send error messages with
 type Relay struct {
-	c      *http.Client
-	domain string
-	to     string
-	from   string
-	key    string
+	transport Transport
+	to        string
+	from      string
+	retry     retryPolicy
+	limiter   *tokenBucket
 }
 
-// Config contains the information used to initialize a Relay
+// Config contains the information used to initialize a Relay. Provider
+// selects which Transport backend New builds; it defaults to
+// "mailgun" when empty so existing config.json files keep working.
 type Config struct {
+	Provider string `json:"provider"`
+	To       string `json:"to"`
+	From     string `json:"from"`
+
+	// Mailgun fields are accepted at the top level for backwards
+	// compatibility with config.json files written before Provider
+	// existed; new configs should prefer the Mailgun sub-struct.
 	Domain string `json:"domain"`
-	To     string `json:"to"`
-	From   string `json:"from"`
 	Key    string `json:"api_key"`
+
+	Mailgun  *MailgunConfig  `json:"mailgun"`
+	SMTP     *SMTPConfig     `json:"smtp"`
+	SES      *SESConfig      `json:"ses"`
+	Sendgrid *SendgridConfig `json:"sendgrid"`
+
+	// Retry tunes the backoff policy used when the transport reports
+	// a transient failure. A nil value uses sensible defaults.
+	Retry *RetryConfig `json:"retry"`
+
+	// RateLimit, if set, caps how fast Send will hand messages to the
+	// transport so a burst of errors can't flood the provider.
+	RateLimit *RateLimitConfig `json:"rate_limit"`
 }
 
 // New is used to generate a new Relay. If called with argument nil, it
 // reads from config.json
 func New(c *Config) (*Relay, error) {
-	// make a new relay
-	r := &Relay{
-		c: &http.Client{},
-	}
-
-	// if they gave us a config, use it
-	if c != nil {
-		r.to = c.To
-		r.from = c.From
-		r.key = c.Key
-		r.domain = c.Domain
-	} else { // otherwise read config.json
+	if c == nil {
 		infile, err := os.Open("config.json")
-		defer infile.Close()
 		if err != nil {
 			return nil, ErrNoConfig
 		}
+		defer infile.Close()
 
 		dec := json.NewDecoder(infile)
 
 		config := new(Config)
-		err = dec.Decode(config)
-		if err != nil {
+		if err := dec.Decode(config); err != nil {
 			return nil, ErrBadConfig
 		}
-
-		r.to = config.To
-		r.from = config.From
-		r.key = config.Key
-		r.domain = config.Domain
+		c = config
 	}
 
-	// ensure all necessary fields are set
-	if r.to == "" || r.from == "" || r.key == "" || r.domain == "" {
+	if c.To == "" || c.From == "" {
 		return nil, ErrBadConfig
-	} else {
-		return r, nil
+	}
+
+	transport, err := newTransport(c)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Relay{
+		transport: transport,
+		to:        c.To,
+		from:      c.From,
+		retry:     newRetryPolicy(c.Retry),
+	}
+
+	if c.RateLimit != nil {
+		r.limiter = newTokenBucket(*c.RateLimit)
+	}
+
+	return r, nil
+}
+
+// newTransport builds the Transport named by c.Provider. An empty
+// Provider means "mailgun", using either the Mailgun sub-struct or the
+// legacy top-level Domain/Key fields.
+func newTransport(c *Config) (Transport, error) {
+	switch c.Provider {
+	case "", "mailgun":
+		cfg := c.Mailgun
+		if cfg == nil {
+			cfg = &MailgunConfig{Domain: c.Domain, Key: c.Key}
+		}
+		return newMailgunTransport(*cfg)
+	case "smtp":
+		if c.SMTP == nil {
+			return nil, ErrBadConfig
+		}
+		return newSMTPTransport(*c.SMTP)
+	case "ses":
+		if c.SES == nil {
+			return nil, ErrBadConfig
+		}
+		return newSESTransport(*c.SES)
+	case "sendgrid":
+		if c.Sendgrid == nil {
+			return nil, ErrBadConfig
+		}
+		return newSendgridTransport(*c.Sendgrid)
+	default:
+		return nil, ErrUnknownProvider
 	}
 }
 
-// Send sends the error 'err' with a timestamp the supplied subject
+// Send sends the error 'err' with a timestamp the supplied subject. It
+// is a convenience wrapper around SendContext using context.Background.
 func (r *Relay) Send(subject string, err error) error {
-	// Collect the information we want to send
+	return r.SendContext(context.Background(), subject, err)
+}
+
+// SendContext sends the error 'err' with a timestamp and the supplied
+// subject, retrying transient transport failures with exponential
+// backoff and honoring the Relay's rate limit, if any. It is a
+// convenience wrapper that builds a Message internally; use
+// SendMessage directly for HTML bodies, attachments, tags, or
+// templates.
+func (r *Relay) SendContext(ctx context.Context, subject string, err error) error {
 	text := time.Now().Format(time.RFC1123) + ":\n" + err.Error()
-	val := make(url.Values)
-	val.Add("from", r.from)
-	val.Add("to", r.to)
-	val.Add("subject", subject)
-	val.Add("text", text)
 
-	// Set up the request
-	req, err := http.NewRequest("POST",
-		"https://api.mailgun.net/v2/"+r.domain+"/messages",
-		bytes.NewReader([]byte(val.Encode())))
+	return r.SendMessage(ctx, Message{
+		Subject: subject,
+		Text:    text,
+	})
+}
 
-	if err != nil {
-		return ErrBadRequest
+// SendMessage sends a fully-formed Message, retrying transient
+// transport failures with exponential backoff and honoring the
+// Relay's rate limit, if any. If msg.To is unset it defaults to the
+// Relay's configured recipient.
+func (r *Relay) SendMessage(ctx context.Context, msg Message) error {
+	msg.from = r.from
+	if len(msg.To) == 0 {
+		msg.To = []string{r.to}
 	}
 
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
-	req.SetBasicAuth("api", r.key)
-
-	// Do the request
-	res, err := r.c.Do(req)
+	return r.send(ctx, msg)
+}
 
-	if err != nil {
-		return err
+func (r *Relay) send(ctx context.Context, msg Message) error {
+	if r.limiter != nil {
+		if err := r.limiter.Wait(ctx); err != nil {
+			return err
+		}
 	}
 
-	// Handle the errors
-	if res.StatusCode == 200 {
-		return nil
-	}
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return ErrBadRequest
-	}
-	if res.StatusCode >= 500 {
-		return ErrMailgunDown
+	start := time.Now()
+	var lastErr error
+
+	for attempt := 1; attempt <= r.retry.maxAttempts; attempt++ {
+		lastErr = r.transport.Send(ctx, msg)
+		if lastErr == nil {
+			return nil
+		}
+
+		terr := asTransportError(lastErr)
+		if terr == nil || !retryableStatus(terr.StatusCode) {
+			return lastErr
+		}
+
+		if attempt == r.retry.maxAttempts || time.Since(start) >= r.retry.maxElapsed {
+			return lastErr
+		}
+
+		delay := r.retry.backoff(attempt)
+		if terr.RetryAfter > 0 {
+			delay = terr.RetryAfter
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
-	return ErrUnknown
+	return lastErr
 }
@@ -0,0 +1,170 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// buildMIMEMessage renders msg as a full RFC 5322 message, including a
+// multipart/alternative text+HTML body and multipart/mixed attachment
+// parts as needed. It is shared by the SMTP transport (which sends it
+// directly over DATA) and the SES transport (which base64-encodes it
+// as a Content.Raw payload).
+func buildMIMEMessage(msg Message) ([]byte, error) {
+	var header bytes.Buffer
+	writeMIMEHeaders(&header, msg)
+
+	switch {
+	case len(msg.Attachments) == 0 && msg.HTML == "":
+		header.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+		header.WriteString(msg.Text)
+		return header.Bytes(), nil
+
+	case len(msg.Attachments) == 0:
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		if err := writeAlternativeParts(w, msg); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&header, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+		header.Write(body.Bytes())
+		return header.Bytes(), nil
+
+	default:
+		var body bytes.Buffer
+		mixed := multipart.NewWriter(&body)
+
+		var bodyErr error
+		if msg.HTML == "" {
+			bodyErr = writeTextPart(mixed, msg.Text)
+		} else {
+			bodyErr = writeNestedAlternative(mixed, msg)
+		}
+		if bodyErr != nil {
+			return nil, bodyErr
+		}
+
+		for _, a := range msg.Attachments {
+			if err := writeAttachmentPart(mixed, a); err != nil {
+				return nil, err
+			}
+		}
+		if err := mixed.Close(); err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&header, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mixed.Boundary())
+		header.Write(body.Bytes())
+		return header.Bytes(), nil
+	}
+}
+
+func writeMIMEHeaders(buf *bytes.Buffer, msg Message) {
+	fmt.Fprintf(buf, "From: %s\r\n", msg.from)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	if len(msg.Cc) > 0 {
+		fmt.Fprintf(buf, "Cc: %s\r\n", strings.Join(msg.Cc, ", "))
+	}
+	fmt.Fprintf(buf, "Subject: %s\r\n", msg.Subject)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+}
+
+func writeTextPart(w *multipart.Writer, text string) error {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", "text/plain; charset=UTF-8")
+
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write([]byte(text))
+	return err
+}
+
+func writeAlternativeParts(w *multipart.Writer, msg Message) error {
+	if err := writeTextPart(w, msg.Text); err != nil {
+		return err
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", "text/html; charset=UTF-8")
+
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write([]byte(msg.HTML))
+	return err
+}
+
+// writeNestedAlternative writes the text/HTML alternative as a single
+// nested multipart/alternative part of w, which is how a mixed
+// envelope (body + attachments) carries a dual-format body.
+func writeNestedAlternative(w *multipart.Writer, msg Message) error {
+	var body bytes.Buffer
+	nested := multipart.NewWriter(&body)
+	if err := writeAlternativeParts(nested, msg); err != nil {
+		return err
+	}
+	if err := nested.Close(); err != nil {
+		return err
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%s", nested.Boundary()))
+
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(body.Bytes())
+	return err
+}
+
+// writeAttachmentPart writes a base64-encoded attachment part, wrapped
+// at the 76-column line length RFC 2045 expects.
+func writeAttachmentPart(w *multipart.Writer, a Attachment) error {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	disposition := "attachment"
+	if a.Inline {
+		disposition = "inline"
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "base64")
+	h.Set("Content-Disposition", fmt.Sprintf(`%s; filename="%s"`, disposition, a.Filename))
+	if a.Inline {
+		h.Set("Content-ID", fmt.Sprintf("<%s>", a.Filename))
+	}
+
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(a.Content)
+	for i := 0; i < len(encoded); i += 76 {
+		end := i + 76
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		if _, err := fmt.Fprintf(part, "%s\r\n", encoded[i:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package relay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// parseMIMEMessage splits a buildMIMEMessage result into its header
+// block and, if it's a multipart message, a *multipart.Reader over the
+// body. It fails the test on any parse error so callers can focus on
+// asserting content.
+func parseMIMEMessage(t *testing.T, raw []byte) (textproto.MIMEHeader, *multipart.Reader) {
+	t.Helper()
+
+	tp := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw)))
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("ReadMIMEHeader: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("ParseMediaType(%q): %v", header.Get("Content-Type"), err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return header, nil
+	}
+
+	return header, multipart.NewReader(tp.R, params["boundary"])
+}
+
+// readPart is a part's header alongside its raw body text and, for
+// base64-encoded parts, the decoded content.
+type readPart struct {
+	header  textproto.MIMEHeader
+	text    string
+	decoded []byte
+}
+
+// readAllParts walks every part of r, reading each one's content
+// immediately: multipart.Reader discards a part's remaining body as
+// soon as NextPart is called again, so parts can't be read lazily
+// after collecting them all.
+func readAllParts(t *testing.T, r *multipart.Reader) []readPart {
+	t.Helper()
+
+	var parts []readPart
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			break
+		}
+
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(part); err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+
+		rp := readPart{header: part.Header, text: buf.String()}
+		if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(buf.String(), "\r\n", ""))
+			if err != nil {
+				t.Fatalf("base64 decode: %v", err)
+			}
+			rp.decoded = decoded
+		}
+		parts = append(parts, rp)
+	}
+	return parts
+}
+
+func TestBuildMIMEMessagePlainTextOnly(t *testing.T) {
+	msg := Message{from: "a@example.com", To: []string{"b@example.com"}, Subject: "hi", Text: "hello there"}
+
+	raw, err := buildMIMEMessage(msg)
+	if err != nil {
+		t.Fatalf("buildMIMEMessage: %v", err)
+	}
+
+	header, multi := parseMIMEMessage(t, raw)
+	if multi != nil {
+		t.Fatalf("Content-Type = %q, want a non-multipart text/plain message", header.Get("Content-Type"))
+	}
+	if !strings.HasPrefix(header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("Content-Type = %q, want text/plain", header.Get("Content-Type"))
+	}
+
+	body := raw[bytes.Index(raw, []byte("\r\n\r\n"))+4:]
+	if string(body) != msg.Text {
+		t.Fatalf("body = %q, want %q", body, msg.Text)
+	}
+}
+
+func TestBuildMIMEMessageAlternativeBody(t *testing.T) {
+	msg := Message{
+		from:    "a@example.com",
+		To:      []string{"b@example.com"},
+		Subject: "hi",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	}
+
+	raw, err := buildMIMEMessage(msg)
+	if err != nil {
+		t.Fatalf("buildMIMEMessage: %v", err)
+	}
+
+	header, multi := parseMIMEMessage(t, raw)
+	if multi == nil {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", header.Get("Content-Type"))
+	}
+
+	parts := readAllParts(t, multi)
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want 2 (text + html)", len(parts))
+	}
+
+	if parts[0].text != msg.Text {
+		t.Fatalf("parts[0] body = %q, want %q", parts[0].text, msg.Text)
+	}
+	if parts[1].text != msg.HTML {
+		t.Fatalf("parts[1] body = %q, want %q", parts[1].text, msg.HTML)
+	}
+}
+
+func TestBuildMIMEMessageMixedWithAttachment(t *testing.T) {
+	content := bytes.Repeat([]byte{0xDE, 0xAD, 0xBE, 0xEF}, 40)
+	msg := Message{
+		from:    "a@example.com",
+		To:      []string{"b@example.com"},
+		Subject: "hi",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+		Attachments: []Attachment{
+			{Filename: "report.bin", ContentType: "application/octet-stream", Content: content},
+		},
+	}
+
+	raw, err := buildMIMEMessage(msg)
+	if err != nil {
+		t.Fatalf("buildMIMEMessage: %v", err)
+	}
+
+	header, multi := parseMIMEMessage(t, raw)
+	if multi == nil {
+		t.Fatalf("Content-Type = %q, want multipart/mixed", header.Get("Content-Type"))
+	}
+
+	parts := readAllParts(t, multi)
+	if len(parts) != 2 {
+		t.Fatalf("got %d top-level parts, want 2 (body + attachment)", len(parts))
+	}
+
+	bodyMediaType, bodyParams, err := mime.ParseMediaType(parts[0].header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("ParseMediaType(body part): %v", err)
+	}
+	if bodyMediaType != "multipart/alternative" {
+		t.Fatalf("body part Content-Type = %q, want multipart/alternative", bodyMediaType)
+	}
+
+	nested := multipart.NewReader(strings.NewReader(parts[0].text), bodyParams["boundary"])
+	nestedParts := readAllParts(t, nested)
+	if len(nestedParts) != 2 {
+		t.Fatalf("got %d nested parts, want 2 (text + html)", len(nestedParts))
+	}
+
+	attachment := parts[1]
+	if got := attachment.header.Get("Content-Transfer-Encoding"); got != "base64" {
+		t.Fatalf("attachment Content-Transfer-Encoding = %q, want base64", got)
+	}
+	if !strings.Contains(attachment.header.Get("Content-Disposition"), `filename="report.bin"`) {
+		t.Fatalf("attachment Content-Disposition = %q, want filename=report.bin", attachment.header.Get("Content-Disposition"))
+	}
+	if !bytes.Equal(attachment.decoded, content) {
+		t.Fatalf("decoded attachment content does not match original")
+	}
+}
+
+func TestWriteAttachmentPartWrapsBase64At76Columns(t *testing.T) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	content := bytes.Repeat([]byte("x"), 500)
+	if err := writeAttachmentPart(w, Attachment{Filename: "f.bin", Content: content}); err != nil {
+		t.Fatalf("writeAttachmentPart: %v", err)
+	}
+	w.Close()
+
+	tp := textproto.NewReader(bufio.NewReader(&buf))
+	mr := multipart.NewReader(tp.R, w.Boundary())
+	part, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+
+	scanner := bufio.NewScanner(part)
+	var lines int
+	for scanner.Scan() {
+		line := scanner.Text()
+		lines++
+		if len(line) > 76 {
+			t.Fatalf("encoded line length = %d, want <= 76", len(line))
+		}
+	}
+	if lines == 0 {
+		t.Fatal("got no encoded lines")
+	}
+}
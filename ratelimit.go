@@ -0,0 +1,83 @@
+package relay
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig bounds how fast a Relay will attempt to send
+// messages, so a spike of errors can't hammer the provider and get
+// the sending domain suspended.
+type RateLimitConfig struct {
+	// RatePerSecond is the steady-state number of sends allowed per
+	// second.
+	RatePerSecond float64 `json:"rate_per_second"`
+
+	// Burst is the number of sends allowed to happen back-to-back
+	// before RatePerSecond starts throttling.
+	Burst int `json:"burst"`
+}
+
+// tokenBucket is a simple token-bucket rate limiter.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	rate   float64
+	last   time.Time
+}
+
+func newTokenBucket(cfg RateLimitConfig) *tokenBucket {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		tokens: float64(burst),
+		max:    float64(burst),
+		rate:   cfg.RatePerSecond,
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	for {
+		wait := b.take()
+		if wait <= 0 {
+			return nil
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// take refills the bucket and either consumes a token (returning 0)
+// or reports how long to wait for one.
+func (b *tokenBucket) take() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(b.max, b.tokens+elapsed*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	if b.rate <= 0 {
+		return time.Second
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
@@ -0,0 +1,229 @@
+/*
+Package relaytest provides an in-process SMTP sink that downstream
+projects can point a relay.Relay at in order to exercise their
+error-reporting paths end-to-end without hitting a real provider.
+*/
+package relaytest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sync"
+	"testing"
+
+	"github.com/packrat386/relay"
+)
+
+// Message is a single mail captured by the Server. To holds every
+// recipient address from the envelope, in RCPT TO order (so To, Cc,
+// and Bcc addresses on the original relay.Message are all present).
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// RelayConfig controls whether the Server forwards every message it
+// captures on to a real upstream MTA, in addition to recording it.
+type RelayConfig struct {
+	// Enabled turns forwarding on. When false (the default) messages
+	// are only captured, never forwarded.
+	Enabled bool
+
+	// Host is the upstream SMTP server ("host:port") to forward
+	// captured messages to.
+	Host string
+}
+
+// Server is an in-process SMTP server that records every message it
+// receives.
+type Server struct {
+	// Relay controls forwarding of captured messages to a real
+	// upstream MTA. It can be changed at any point; it is read fresh
+	// for every incoming message.
+	Relay RelayConfig
+
+	ln net.Listener
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewServer starts a Server listening on an arbitrary local port and
+// registers a cleanup with t so it is shut down when the test
+// finishes.
+func NewServer(t *testing.T) *Server {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("relaytest: failed to listen: %v", err)
+	}
+
+	s := &Server{ln: ln}
+	go s.serve()
+
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// Addr returns the "host:port" the Server is listening on.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Config returns a relay.Config pre-wired to send mail to this
+// Server via the "smtp" provider.
+func (s *Server) Config(to, from string) *relay.Config {
+	host, port := splitHostPort(s.Addr())
+
+	return &relay.Config{
+		Provider: "smtp",
+		To:       to,
+		From:     from,
+		SMTP: &relay.SMTPConfig{
+			Host: host,
+			Port: port,
+		},
+	}
+}
+
+// Messages returns every message captured so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Message, len(s.messages))
+	copy(out, s.messages)
+	return out
+}
+
+// Reset discards all captured messages.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = nil
+}
+
+// Close stops the Server from accepting further connections.
+func (s *Server) Close() {
+	s.ln.Close()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	w := bufio.NewWriter(conn)
+
+	tp.PrintfLine("220 relaytest ESMTP ready")
+
+	var from string
+	var to []string
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd, arg := splitCommand(line)
+
+		switch cmd {
+		case "HELO", "EHLO":
+			tp.PrintfLine("250 relaytest")
+		case "MAIL":
+			from = extractAddr(arg)
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			to = append(to, extractAddr(arg))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			s.capture(Message{From: from, To: to, Data: data})
+			tp.PrintfLine("250 OK")
+		case "RSET":
+			from, to = "", nil
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			w.Flush()
+			return
+		default:
+			tp.PrintfLine("500 unrecognized command")
+		}
+	}
+}
+
+func (s *Server) capture(msg Message) {
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	relayCfg := s.Relay
+	s.mu.Unlock()
+
+	if relayCfg.Enabled && relayCfg.Host != "" {
+		// best-effort: forwarding failures are ignored and don't
+		// affect the original SMTP transaction.
+		_ = smtp.SendMail(relayCfg.Host, nil, msg.From, msg.To, msg.Data)
+	}
+}
+
+func splitCommand(line string) (cmd, arg string) {
+	for i, r := range line {
+		if r == ' ' || r == ':' {
+			return line[:i], line[i+1:]
+		}
+	}
+	return line, ""
+}
+
+// extractAddr pulls the email address out of a MAIL FROM:<addr> or
+// RCPT TO:<addr> argument, stripping the angle brackets.
+func extractAddr(arg string) string {
+	start := 0
+	end := len(arg)
+	for i, r := range arg {
+		if r == '<' {
+			start = i + 1
+		}
+		if r == '>' {
+			end = i
+		}
+	}
+	if start > end {
+		return arg
+	}
+	return arg[start:end]
+}
+
+func splitHostPort(addr string) (string, int) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, 0
+	}
+
+	var port int
+	fmt.Sscanf(portStr, "%d", &port)
+	return host, port
+}
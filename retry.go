@@ -0,0 +1,167 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TransportError carries the HTTP-style status code a Transport
+// observed (or synthesized, for raw network failures) so the retry
+// policy can decide whether it's worth trying again.
+type TransportError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *TransportError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("relay: transport returned status %d", e.StatusCode)
+}
+
+func (e *TransportError) Unwrap() error { return e.Err }
+
+func retryableStatus(code int) bool {
+	return code == 429 || code >= 500
+}
+
+// classifyNetErr maps a raw network error (timeout, connection reset,
+// DNS failure) onto the synthetic status code the rest of the retry
+// machinery understands, so callers get consistent TransportErrors
+// instead of having to sniff net.OpError themselves.
+func classifyNetErr(err error) *TransportError {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return &TransportError{StatusCode: 404, Err: err}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &TransportError{StatusCode: 408, Err: err}
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return &TransportError{StatusCode: 503, Err: err}
+	}
+
+	return nil
+}
+
+// asTransportError normalizes any error a Transport returned into a
+// *TransportError, synthesizing one from a raw network error if
+// needed. It returns nil for errors the retry policy has no opinion
+// about, which Send treats as non-retryable.
+func asTransportError(err error) *TransportError {
+	var terr *TransportError
+	if errors.As(err, &terr) {
+		return terr
+	}
+	return classifyNetErr(err)
+}
+
+// statusToError turns an HTTP response's status code into a
+// *TransportError, using clientErr/serverErr as the wrapped sentinel
+// for the 4xx/5xx ranges so existing errors.Is(err, relay.ErrBadRequest)
+// style checks keep working. 429 responses carry whatever Retry-After
+// the server sent along.
+func statusToError(res *http.Response, clientErr, serverErr error) error {
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return nil
+	case res.StatusCode == 429:
+		return &TransportError{
+			StatusCode: 429,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+			Err:        serverErr,
+		}
+	case res.StatusCode >= 400 && res.StatusCode < 500:
+		return &TransportError{StatusCode: res.StatusCode, Err: clientErr}
+	case res.StatusCode >= 500:
+		return &TransportError{StatusCode: res.StatusCode, Err: serverErr}
+	default:
+		return &TransportError{StatusCode: res.StatusCode, Err: ErrUnknown}
+	}
+}
+
+// parseRetryAfter interprets an HTTP Retry-After header, which may be
+// either a number of seconds or an HTTP-date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// RetryConfig tunes the backoff policy Relay uses when a Transport
+// reports a transient failure.
+type RetryConfig struct {
+	// MaxAttempts is the total number of times Send will try the
+	// transport, including the first attempt.
+	MaxAttempts int `json:"max_attempts"`
+
+	// MaxElapsed bounds the total wall-clock time spent retrying.
+	// Once exceeded, Send returns the last error without sleeping
+	// again.
+	MaxElapsed time.Duration `json:"max_elapsed"`
+}
+
+const (
+	defaultMaxAttempts = 5
+	defaultMaxElapsed  = 2 * time.Minute
+	defaultBaseDelay   = 250 * time.Millisecond
+	defaultMaxDelay    = 30 * time.Second
+)
+
+type retryPolicy struct {
+	maxAttempts int
+	maxElapsed  time.Duration
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func newRetryPolicy(cfg *RetryConfig) retryPolicy {
+	p := retryPolicy{
+		maxAttempts: defaultMaxAttempts,
+		maxElapsed:  defaultMaxElapsed,
+		baseDelay:   defaultBaseDelay,
+		maxDelay:    defaultMaxDelay,
+	}
+
+	if cfg != nil {
+		if cfg.MaxAttempts > 0 {
+			p.maxAttempts = cfg.MaxAttempts
+		}
+		if cfg.MaxElapsed > 0 {
+			p.maxElapsed = cfg.MaxElapsed
+		}
+	}
+
+	return p
+}
+
+// backoff returns how long to sleep before retry number `attempt`
+// (the attempt that just failed was number attempt), using exponential
+// backoff with full jitter.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.baseDelay) * math.Pow(2, float64(attempt-1))
+	if d > float64(p.maxDelay) {
+		d = float64(p.maxDelay)
+	}
+
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
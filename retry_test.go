@@ -0,0 +1,123 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingTransport returns err for the first `failures` calls, then
+// succeeds, recording how many times Send was called.
+type countingTransport struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (t *countingTransport) Send(ctx context.Context, msg Message) error {
+	t.calls++
+	if t.calls <= t.failures {
+		return t.err
+	}
+	return nil
+}
+
+func TestSendRetriesOnRetryableStatus(t *testing.T) {
+	transport := &countingTransport{
+		failures: 2,
+		err:      &TransportError{StatusCode: 500, Err: ErrMailgunDown},
+	}
+
+	r := &Relay{
+		transport: transport,
+		to:        "to@example.com",
+		from:      "from@example.com",
+		retry: retryPolicy{
+			maxAttempts: 5,
+			maxElapsed:  time.Second,
+			baseDelay:   time.Millisecond,
+			maxDelay:    time.Millisecond,
+		},
+	}
+
+	if err := r.SendContext(context.Background(), "subject", errors.New("boom")); err != nil {
+		t.Fatalf("SendContext returned %v, want nil after transport recovers", err)
+	}
+
+	if transport.calls != 3 {
+		t.Fatalf("transport.calls = %d, want 3 (2 failures + 1 success)", transport.calls)
+	}
+}
+
+func TestSendStopsAfterMaxAttempts(t *testing.T) {
+	transport := &countingTransport{
+		failures: 10,
+		err:      &TransportError{StatusCode: 500, Err: ErrMailgunDown},
+	}
+
+	r := &Relay{
+		transport: transport,
+		to:        "to@example.com",
+		from:      "from@example.com",
+		retry: retryPolicy{
+			maxAttempts: 3,
+			maxElapsed:  time.Second,
+			baseDelay:   time.Millisecond,
+			maxDelay:    time.Millisecond,
+		},
+	}
+
+	err := r.SendContext(context.Background(), "subject", errors.New("boom"))
+	if !errors.Is(err, ErrMailgunDown) {
+		t.Fatalf("SendContext error = %v, want ErrMailgunDown", err)
+	}
+
+	if transport.calls != 3 {
+		t.Fatalf("transport.calls = %d, want 3 (maxAttempts)", transport.calls)
+	}
+}
+
+func TestSendDoesNotRetryNonRetryableStatus(t *testing.T) {
+	transport := &countingTransport{
+		failures: 10,
+		err:      &TransportError{StatusCode: 400, Err: ErrBadRequest},
+	}
+
+	r := &Relay{
+		transport: transport,
+		to:        "to@example.com",
+		from:      "from@example.com",
+		retry: retryPolicy{
+			maxAttempts: 5,
+			maxElapsed:  time.Second,
+			baseDelay:   time.Millisecond,
+			maxDelay:    time.Millisecond,
+		},
+	}
+
+	err := r.SendContext(context.Background(), "subject", errors.New("boom"))
+	if !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("SendContext error = %v, want ErrBadRequest", err)
+	}
+
+	if transport.calls != 1 {
+		t.Fatalf("transport.calls = %d, want 1 (no retry on non-retryable status)", transport.calls)
+	}
+}
+
+func TestBackoffStaysWithinMaxDelay(t *testing.T) {
+	p := retryPolicy{
+		maxAttempts: 10,
+		maxElapsed:  time.Minute,
+		baseDelay:   250 * time.Millisecond,
+		maxDelay:    2 * time.Second,
+	}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := p.backoff(attempt)
+		if d < 0 || d > p.maxDelay {
+			t.Fatalf("backoff(%d) = %v, want in [0, %v]", attempt, d, p.maxDelay)
+		}
+	}
+}
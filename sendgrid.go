@@ -0,0 +1,154 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// SendgridConfig holds the information needed to send mail through the
+// Sendgrid v3 HTTP API.
+type SendgridConfig struct {
+	Key string `json:"api_key"`
+
+	// BaseURL overrides the Sendgrid API base URL entirely, e.g. to
+	// point at a mock server in tests. Defaults to
+	// "https://api.sendgrid.com/v3".
+	BaseURL string `json:"base_url"`
+}
+
+// sendgridTransport sends messages using the Sendgrid v3 /mail/send API.
+type sendgridTransport struct {
+	c       *http.Client
+	key     string
+	baseURL string
+}
+
+func newSendgridTransport(cfg SendgridConfig) (*sendgridTransport, error) {
+	if cfg.Key == "" {
+		return nil, ErrBadConfig
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.sendgrid.com/v3"
+	}
+
+	return &sendgridTransport{
+		c:       &http.Client{},
+		key:     cfg.Key,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+type sendgridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendgridPersonalization struct {
+	To  []sendgridAddress `json:"to"`
+	Cc  []sendgridAddress `json:"cc,omitempty"`
+	Bcc []sendgridAddress `json:"bcc,omitempty"`
+}
+
+type sendgridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendgridMail struct {
+	Personalizations []sendgridPersonalization `json:"personalizations"`
+	From             sendgridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendgridContent         `json:"content,omitempty"`
+	Attachments      []sendgridAttachment      `json:"attachments,omitempty"`
+	Categories       []string                  `json:"categories,omitempty"`
+	TemplateID       string                    `json:"template_id,omitempty"`
+	CustomArgs       map[string]any            `json:"custom_args,omitempty"`
+}
+
+// sendgridAttachment mirrors the subset of Sendgrid's attachment object
+// relay uses; Content is base64-encoded per the API's requirements.
+type sendgridAttachment struct {
+	Content     string `json:"content"`
+	Filename    string `json:"filename"`
+	Type        string `json:"type,omitempty"`
+	Disposition string `json:"disposition,omitempty"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+func (t *sendgridTransport) Send(ctx context.Context, msg Message) error {
+	mail := sendgridMail{
+		Personalizations: []sendgridPersonalization{{
+			To:  addresses(msg.To),
+			Cc:  addresses(msg.Cc),
+			Bcc: addresses(msg.Bcc),
+		}},
+		From:       sendgridAddress{Email: msg.from},
+		Subject:    msg.Subject,
+		Categories: msg.Tags,
+		TemplateID: msg.TemplateName,
+		CustomArgs: msg.Vars,
+	}
+
+	if msg.Text != "" {
+		mail.Content = append(mail.Content, sendgridContent{Type: "text/plain", Value: msg.Text})
+	}
+	if msg.HTML != "" {
+		mail.Content = append(mail.Content, sendgridContent{Type: "text/html", Value: msg.HTML})
+	}
+
+	for _, a := range msg.Attachments {
+		disposition := "attachment"
+		var contentID string
+		if a.Inline {
+			disposition = "inline"
+			contentID = a.Filename
+		}
+
+		mail.Attachments = append(mail.Attachments, sendgridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(a.Content),
+			Filename:    a.Filename,
+			Type:        a.ContentType,
+			Disposition: disposition,
+			ContentID:   contentID,
+		})
+	}
+
+	body, err := json.Marshal(mail)
+	if err != nil {
+		return ErrBadRequest
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		t.baseURL+"/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return ErrBadRequest
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.key)
+
+	res, err := t.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return statusToError(res, ErrBadRequest, ErrMailgunDown)
+}
+
+func addresses(addrs []string) []sendgridAddress {
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	out := make([]sendgridAddress, len(addrs))
+	for i, a := range addrs {
+		out[i] = sendgridAddress{Email: a}
+	}
+	return out
+}
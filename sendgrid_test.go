@@ -0,0 +1,94 @@
+package relay
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendgridTransportSend(t *testing.T) {
+	var captured sendgridMail
+	var authHeader string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mail/send" {
+			t.Errorf("request path = %q, want /mail/send", r.URL.Path)
+		}
+		authHeader = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	transport, err := newSendgridTransport(SendgridConfig{Key: "sg-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("newSendgridTransport: %v", err)
+	}
+
+	msg := Message{
+		from:    "from@example.com",
+		To:      []string{"to@example.com"},
+		Cc:      []string{"cc@example.com"},
+		Subject: "hi",
+		Text:    "hello",
+		HTML:    "<p>hello</p>",
+		Attachments: []Attachment{
+			{Filename: "f.txt", ContentType: "text/plain", Content: []byte("attachment body")},
+		},
+	}
+
+	if err := transport.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if authHeader != "Bearer sg-key" {
+		t.Fatalf("Authorization = %q, want %q", authHeader, "Bearer sg-key")
+	}
+	if len(captured.Personalizations) != 1 || len(captured.Personalizations[0].To) != 1 ||
+		captured.Personalizations[0].To[0].Email != "to@example.com" {
+		t.Fatalf("Personalizations = %+v, want one entry addressed to to@example.com", captured.Personalizations)
+	}
+	if len(captured.Personalizations[0].Cc) != 1 || captured.Personalizations[0].Cc[0].Email != "cc@example.com" {
+		t.Fatalf("Personalizations[0].Cc = %+v, want cc@example.com", captured.Personalizations[0].Cc)
+	}
+	if len(captured.Content) != 2 {
+		t.Fatalf("got %d content blocks, want 2 (text + html)", len(captured.Content))
+	}
+	if len(captured.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1", len(captured.Attachments))
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(captured.Attachments[0].Content)
+	if err != nil {
+		t.Fatalf("decoding attachment content: %v", err)
+	}
+	if string(decoded) != "attachment body" {
+		t.Fatalf("decoded attachment = %q, want %q", decoded, "attachment body")
+	}
+	if captured.Attachments[0].Disposition != "attachment" {
+		t.Fatalf("Attachments[0].Disposition = %q, want attachment", captured.Attachments[0].Disposition)
+	}
+}
+
+func TestSendgridTransportSendPropagatesErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	transport, err := newSendgridTransport(SendgridConfig{Key: "sg-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("newSendgridTransport: %v", err)
+	}
+
+	err = transport.Send(context.Background(), Message{from: "from@example.com", To: []string{"to@example.com"}})
+	terr := asTransportError(err)
+	if terr == nil || terr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Send error = %v, want a TransportError with status 429", err)
+	}
+}
@@ -0,0 +1,124 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSESTransportSendSimple(t *testing.T) {
+	var captured sesSendEmailRequest
+	var authHeader string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	transport, err := newSESTransport(SESConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		BaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("newSESTransport: %v", err)
+	}
+
+	msg := Message{
+		from:    "from@example.com",
+		To:      []string{"to@example.com"},
+		Subject: "hi",
+		Text:    "hello",
+	}
+
+	if err := transport.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if captured.FromEmailAddress != msg.from {
+		t.Fatalf("FromEmailAddress = %q, want %q", captured.FromEmailAddress, msg.from)
+	}
+	if captured.Content.Simple == nil || captured.Content.Simple.Body.Text == nil || captured.Content.Simple.Body.Text.Data != "hello" {
+		t.Fatalf("Content.Simple = %+v, want a Simple body with Text.Data = %q", captured.Content.Simple, "hello")
+	}
+	if captured.Content.Raw != nil {
+		t.Fatalf("Content.Raw = %+v, want nil for a message with no attachments", captured.Content.Raw)
+	}
+	if !strings.HasPrefix(authHeader, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+		t.Fatalf("Authorization = %q, want an AWS4-HMAC-SHA256 header for AKIDEXAMPLE", authHeader)
+	}
+}
+
+func TestSESTransportSendWithAttachmentUsesRaw(t *testing.T) {
+	var captured sesSendEmailRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	transport, err := newSESTransport(SESConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		BaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("newSESTransport: %v", err)
+	}
+
+	msg := Message{
+		from:    "from@example.com",
+		To:      []string{"to@example.com"},
+		Subject: "hi",
+		Text:    "hello",
+		Attachments: []Attachment{
+			{Filename: "f.txt", Content: []byte("attachment body")},
+		},
+	}
+
+	if err := transport.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if captured.Content.Simple != nil {
+		t.Fatalf("Content.Simple = %+v, want nil when the message has attachments", captured.Content.Simple)
+	}
+	if captured.Content.Raw == nil || captured.Content.Raw.Data == "" {
+		t.Fatal("Content.Raw is nil or empty, want a base64-encoded MIME message")
+	}
+}
+
+func TestSESTransportSendPropagatesErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	transport, err := newSESTransport(SESConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		BaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("newSESTransport: %v", err)
+	}
+
+	err = transport.Send(context.Background(), Message{from: "from@example.com", To: []string{"to@example.com"}})
+	terr := asTransportError(err)
+	if terr == nil || terr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Send error = %v, want a TransportError with status 500", err)
+	}
+}
@@ -0,0 +1,153 @@
+package relay
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPConfig holds the information needed to send mail through a plain
+// SMTP server, with optional STARTTLS.
+type SMTPConfig struct {
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	UseSTARTTLS bool   `json:"use_starttls"`
+
+	// TLSConfig is used for the STARTTLS handshake. If nil, a default
+	// tls.Config is built using Host as the server name.
+	TLSConfig *tls.Config `json:"-"`
+}
+
+// smtpTransport sends messages over SMTP, optionally upgrading the
+// connection with STARTTLS and authenticating with whatever mechanism
+// the server advertises in its EHLO response.
+type smtpTransport struct {
+	host     string
+	addr     string
+	username string
+	password string
+	starttls bool
+	tlsCfg   *tls.Config
+}
+
+func newSMTPTransport(cfg SMTPConfig) (*smtpTransport, error) {
+	if cfg.Host == "" || cfg.Port == 0 {
+		return nil, ErrBadConfig
+	}
+
+	tlsCfg := cfg.TLSConfig
+	if tlsCfg == nil {
+		tlsCfg = &tls.Config{ServerName: cfg.Host}
+	}
+
+	return &smtpTransport{
+		host:     cfg.Host,
+		addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		username: cfg.Username,
+		password: cfg.Password,
+		starttls: cfg.UseSTARTTLS,
+		tlsCfg:   tlsCfg,
+	}, nil
+}
+
+func (t *smtpTransport) Send(ctx context.Context, msg Message) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client, err := smtp.NewClient(conn, t.host)
+	if err != nil {
+		return ErrBadRequest
+	}
+	defer client.Close()
+
+	if t.starttls {
+		ok, _ := client.Extension("STARTTLS")
+		if !ok {
+			// Fail closed: proceeding here would mean authenticating (and
+			// sending the message) in plaintext, which is exactly what a
+			// STARTTLS-stripping MITM relies on.
+			return ErrSTARTTLSUnavailable
+		}
+		if err := client.StartTLS(t.tlsCfg); err != nil {
+			return err
+		}
+	}
+
+	if t.username != "" {
+		if err := t.authenticate(client); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(msg.from); err != nil {
+		return ErrBadRequest
+	}
+	for _, rcpt := range allRecipients(msg) {
+		if err := client.Rcpt(rcpt); err != nil {
+			return ErrBadRequest
+		}
+	}
+
+	raw, err := buildMIMEMessage(msg)
+	if err != nil {
+		return ErrBadRequest
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return ErrBadRequest
+	}
+
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// authenticate picks PLAIN or LOGIN depending on what the server
+// advertised in its EHLO extensions, preferring PLAIN when both are
+// available.
+func (t *smtpTransport) authenticate(client *smtp.Client) error {
+	ok, authExt := client.Extension("AUTH")
+	if !ok {
+		return nil
+	}
+
+	mechanisms := strings.Fields(authExt)
+	for _, m := range mechanisms {
+		if m == "PLAIN" {
+			return client.Auth(smtp.PlainAuth("", t.username, t.password, t.host))
+		}
+	}
+	for _, m := range mechanisms {
+		if m == "LOGIN" {
+			return client.Auth(loginAuth(t.username, t.password))
+		}
+	}
+
+	return nil
+}
+
+// allRecipients flattens To/Cc/Bcc into the address list SMTP RCPT TO
+// needs; Bcc addresses get an envelope recipient but, as usual, no Bcc
+// header in the message body.
+func allRecipients(msg Message) []string {
+	rcpts := make([]string, 0, len(msg.To)+len(msg.Cc)+len(msg.Bcc))
+	rcpts = append(rcpts, msg.To...)
+	rcpts = append(rcpts, msg.Cc...)
+	rcpts = append(rcpts, msg.Bcc...)
+	return rcpts
+}
@@ -0,0 +1,36 @@
+package relay
+
+import (
+	"errors"
+	"net/smtp"
+)
+
+// loginAuthMechanism implements the LOGIN SASL mechanism, which
+// net/smtp does not provide out of the box.
+type loginAuthMechanism struct {
+	username string
+	password string
+}
+
+func loginAuth(username, password string) smtp.Auth {
+	return &loginAuthMechanism{username: username, password: password}
+}
+
+func (a *loginAuthMechanism) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuthMechanism) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch string(fromServer) {
+	case "Username:":
+		return []byte(a.username), nil
+	case "Password:":
+		return []byte(a.password), nil
+	default:
+		return nil, errors.New("relay: unexpected LOGIN auth prompt from server")
+	}
+}
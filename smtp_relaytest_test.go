@@ -0,0 +1,104 @@
+package relay_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/packrat386/relay"
+	"github.com/packrat386/relay/relaytest"
+)
+
+func TestSMTPTransportCapturesEveryRecipient(t *testing.T) {
+	srv := relaytest.NewServer(t)
+
+	r, err := relay.New(srv.Config("to@example.com", "from@example.com"))
+	if err != nil {
+		t.Fatalf("relay.New: %v", err)
+	}
+
+	err = r.SendMessage(context.Background(), relay.Message{
+		To:      []string{"to@example.com"},
+		Cc:      []string{"cc@example.com"},
+		Bcc:     []string{"bcc@example.com"},
+		Subject: "hi",
+		Text:    "hello",
+	})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d captured messages, want 1", len(msgs))
+	}
+
+	want := map[string]bool{"to@example.com": true, "cc@example.com": true, "bcc@example.com": true}
+	if len(msgs[0].To) != len(want) {
+		t.Fatalf("captured To = %v, want all of %v", msgs[0].To, want)
+	}
+	for _, addr := range msgs[0].To {
+		if !want[addr] {
+			t.Fatalf("captured unexpected recipient %q", addr)
+		}
+	}
+}
+
+func TestSMTPTransportDeliversAttachment(t *testing.T) {
+	srv := relaytest.NewServer(t)
+
+	r, err := relay.New(srv.Config("to@example.com", "from@example.com"))
+	if err != nil {
+		t.Fatalf("relay.New: %v", err)
+	}
+
+	err = r.SendMessage(context.Background(), relay.Message{
+		Subject: "hi",
+		Text:    "hello",
+		Attachments: []relay.Attachment{
+			{Filename: "report.txt", ContentType: "text/plain", Content: []byte("attachment contents")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d captured messages, want 1", len(msgs))
+	}
+
+	encoded := base64.StdEncoding.EncodeToString([]byte("attachment contents"))
+	// The message wraps base64 at 76 columns, so look for the encoded
+	// content with any embedded line breaks removed.
+	data := strings.NewReplacer("\r\n", "", "\n", "").Replace(string(msgs[0].Data))
+	if !strings.Contains(data, encoded) {
+		t.Fatalf("captured DATA does not contain the base64-encoded attachment; got:\n%s", msgs[0].Data)
+	}
+	if !strings.Contains(string(msgs[0].Data), `filename="report.txt"`) {
+		t.Fatalf("captured DATA does not contain the attachment filename; got:\n%s", msgs[0].Data)
+	}
+}
+
+func TestSMTPTransportFailsClosedWithoutSTARTTLS(t *testing.T) {
+	srv := relaytest.NewServer(t)
+
+	cfg := srv.Config("to@example.com", "from@example.com")
+	cfg.SMTP.UseSTARTTLS = true
+
+	r, err := relay.New(cfg)
+	if err != nil {
+		t.Fatalf("relay.New: %v", err)
+	}
+
+	err = r.SendMessage(context.Background(), relay.Message{Subject: "hi", Text: "hello"})
+	if !errors.Is(err, relay.ErrSTARTTLSUnavailable) {
+		t.Fatalf("SendMessage error = %v, want ErrSTARTTLSUnavailable (relaytest's server doesn't advertise STARTTLS)", err)
+	}
+
+	if len(srv.Messages()) != 0 {
+		t.Fatalf("got %d captured messages, want 0 (send should have failed before DATA)", len(srv.Messages()))
+	}
+}
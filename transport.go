@@ -0,0 +1,43 @@
+package relay
+
+import "context"
+
+// Attachment is a file attached to an outbound Message. Every built-in
+// Transport supports it. When Inline is true, it's exposed as an inline
+// image referenced from HTML via its Filename as a Content-ID, rather
+// than as a regular attachment.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+	Inline      bool
+}
+
+// Message is the full set of fields used to compose an outbound email.
+// Send and SendContext build a Message with only Subject and Text set;
+// SendMessage exposes the rest.
+type Message struct {
+	// from is filled in by Relay from Config.From. It isn't exported
+	// because, like To for Send/SendContext, the sending address is a
+	// property of the Relay rather than of an individual message.
+	from string
+
+	To           []string
+	Cc           []string
+	Bcc          []string
+	Subject      string
+	Text         string
+	HTML         string
+	Attachments  []Attachment
+	Tags         []string
+	Vars         map[string]any
+	TemplateName string
+}
+
+// Transport delivers a single Message through some email provider.
+// Relay delegates all wire-format and protocol concerns to whichever
+// Transport it was built with, so swapping providers never requires
+// changing the call sites that call Send.
+type Transport interface {
+	Send(ctx context.Context, msg Message) error
+}